@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v2"
 	"os"
+	"strings"
+	"time"
 )
 
 // DiskRecord contains a mapping of an on-disk directory or file to a web location within our server. It is exported
@@ -18,6 +20,10 @@ type DiskRecord struct {
 
 	// An optional content-type, if it's necessary to manually override what might be auto-detected.
 	ContentType string `yaml:"content-type"`
+
+	// Source optionally names a serving.Source registered via Server.RegisterSource that should handle this
+	// mount instead of the default disk-backed one.
+	Source string `yaml:"source"`
 }
 
 // Redirect contains a redirection mapping, marking that a given web path should be served as a redirection notice
@@ -33,6 +39,85 @@ type Redirect struct {
 	Code int `yaml:"code"`
 }
 
+// LoggingConfig controls access and error logging for a Server. It is exported simply so that it can be used in
+// a ServerConfig.
+type LoggingConfig struct {
+
+	// AccessLog is where access log lines are written: a file path, or the special values "stdout"/"stderr".
+	// Leaving it empty disables access logging.
+	AccessLog string `yaml:"access_log"`
+
+	// Format selects the access log line format: "common", "combined", or "json". Defaults to "common".
+	Format string `yaml:"format"`
+
+	// ErrorLog is where error log lines are written: a file path, or the special values "stdout"/"stderr".
+	// Leaving it empty sends error lines to the standard log package's output instead.
+	ErrorLog string `yaml:"error_log"`
+}
+
+// ACMEConfig controls automatic certificate provisioning for the https server via ACME (e.g. Let's Encrypt). It
+// is exported simply so that it can be used in a ServerConfig.
+type ACMEConfig struct {
+
+	// Enabled turns on ACME-managed certificates in place of a static CertificateFile/KeyFile pair.
+	Enabled bool `yaml:"enabled"`
+
+	// Email is the contact address registered with the ACME CA.
+	Email string `yaml:"email"`
+
+	// Domains is the set of hostnames we should request and renew certificates for.
+	Domains []string `yaml:"domains"`
+
+	// CacheDir is where issued certificates and account keys are cached on disk between restarts.
+	CacheDir string `yaml:"cache_dir"`
+
+	// Staging, when true, uses the ACME CA's staging directory instead of production, so testing doesn't burn
+	// against production rate limits.
+	Staging bool `yaml:"staging"`
+}
+
+// MiddlewareConfig describes one entry in a ServerConfig's top-level middleware: list, letting config-file users
+// apply the same built-in middleware that Go code gets via Server.Use/Server.UseAt. It is exported simply so
+// that it can be used in a ServerConfig.
+type MiddlewareConfig struct {
+
+	// Type selects which built-in middleware this entry configures: "gzip", "cors", "basic-auth", or
+	// "rate-limit".
+	Type string `yaml:"type"`
+
+	// PathPrefix scopes this middleware to handlers registered under it, the same as Server.UseAt. Left
+	// empty, the middleware applies globally, the same as Server.Use.
+	PathPrefix string `yaml:"path-prefix"`
+
+	// GzipLevel is used when Type is "gzip" (see compress/gzip for valid values; 0 uses the default).
+	GzipLevel int `yaml:"gzip-level"`
+
+	// CORS is used when Type is "cors".
+	CORS struct {
+		AllowedOrigins   []string      `yaml:"allowed-origins"`
+		AllowedMethods   []string      `yaml:"allowed-methods"`
+		AllowedHeaders   []string      `yaml:"allowed-headers"`
+		AllowCredentials bool          `yaml:"allow-credentials"`
+		MaxAge           time.Duration `yaml:"max-age"`
+	} `yaml:"cors"`
+
+	// BasicAuth is used when Type is "basic-auth". Users maps username to a bcrypt password hash.
+	BasicAuth struct {
+		Realm string            `yaml:"realm"`
+		Users map[string]string `yaml:"users"`
+	} `yaml:"basic-auth"`
+
+	// RateLimit is used when Type is "rate-limit".
+	RateLimit struct {
+		RequestsPerSecond float64 `yaml:"requests-per-second"`
+		Burst             int     `yaml:"burst"`
+
+		// TrustedProxies lists the CIDR ranges of reverse proxies allowed to set X-Forwarded-For; see
+		// middleware.RateLimitOptions.TrustedProxies for why this matters.
+		TrustedProxies []string `yaml:"trusted-proxies"`
+	} `yaml:"rate-limit"`
+}
+
 // ServerConfig encapsulates the configuration of a SoftServe server, suitable for loading from or saving
 // to a yaml or XML file. While there is a helper function to load from a YAML file, you can also embed the
 // ServerConfig struct in your own more expansive configuration, and it will parse properly as a child element.
@@ -61,10 +146,23 @@ type ServerConfig struct {
 		CertificateFile string `yaml:"certificate"`
 
 		// KeyFile is the on-disk path to the key for the CertificateFile.
-		KeyFile string `yaml:"key""`
+		KeyFile string `yaml:"key"`
 
 		// CACertFile is the on-disk path to the certificate authority who signed CertificateFile.
 		CACertFile string `yaml:"authority"`
+
+		// SelfSigned, when true and neither CertificateFile nor KeyFile are set, generates an in-memory
+		// certificate at startup covering localhost (and any SelfSignedHosts given), so HTTPS works locally
+		// without provisioning real certificate files.
+		SelfSigned bool `yaml:"self_signed"`
+
+		// SelfSignedHosts lists extra hostnames the generated self-signed certificate should cover, beyond
+		// the always-included "localhost". Only used when SelfSigned is true.
+		SelfSignedHosts []string `yaml:"self_signed_hosts"`
+
+		// ACME configures automatic certificate provisioning, as an alternative to CertificateFile/KeyFile
+		// or SelfSigned.
+		ACME ACMEConfig `yaml:"acme"`
 	} `yaml:"https" xml:"SecureServer"`
 
 	// DocumentRoot is where we should serve files from, by default.
@@ -78,6 +176,27 @@ type ServerConfig struct {
 
 	// Redirects contains any redirects we want to add to our server.
 	Redirects []Redirect `yaml:"redirects" xml:">Redirect"`
+
+	// Logging controls access and error logging for the server.
+	Logging LoggingConfig `yaml:"logging"`
+
+	// Middleware lists config-driven middleware to install, equivalent to calling Server.Use/Server.UseAt
+	// directly from Go.
+	Middleware []MiddlewareConfig `yaml:"middleware"`
+
+	// ShutdownTimeout bounds how long Stop will wait for in-flight requests to finish once shutdown has begun.
+	// A zero value means wait as long as the context passed to Stop allows.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// LameDuckPeriod is how long a server should keep rejecting new requests with a 503 before it actually
+	// begins shutting down, giving load balancers time to notice and stop routing traffic here. A zero value
+	// skips the lame-duck period entirely.
+	LameDuckPeriod time.Duration `yaml:"lame_duck_period"`
+
+	// Watch, when true and the server was configured via Server.ConfigureFromFile, watches the config file
+	// and every served file/directory for changes and calls Server.Reload automatically. It has no effect
+	// on a Server configured directly via Server.Configure.
+	Watch bool `yaml:"watch"`
 }
 
 // Initialize resets a ServerConfig to the default values.
@@ -91,6 +210,9 @@ func (sc *ServerConfig) Initialize() {
 	sc.Secure.CertificateFile = ""
 	sc.Secure.KeyFile = ""
 	sc.Secure.CACertFile = ""
+	sc.Secure.SelfSigned = false
+	sc.Secure.SelfSignedHosts = nil
+	sc.Secure.ACME = ACMEConfig{}
 
 	sc.DocumentRoot = ""
 
@@ -98,6 +220,14 @@ func (sc *ServerConfig) Initialize() {
 	sc.Files = make([]DiskRecord, 0)
 	sc.Redirects = make([]Redirect, 0)
 
+	sc.Logging = LoggingConfig{}
+	sc.Middleware = make([]MiddlewareConfig, 0)
+
+	sc.ShutdownTimeout = 0
+	sc.LameDuckPeriod = 0
+
+	sc.Watch = false
+
 }
 
 // ReadConfigYAML does what you'd expect; it reads a configuration from a provided YAML file.
@@ -140,9 +270,24 @@ func (sc *ServerConfig) Validate() error {
 		return errors.New("neither https or http servers are enabled; we have nothing to do")
 	}
 
-	// Check that https is correctly configured.
-	if sc.Secure.Enabled && (len(sc.Secure.CertificateFile) == 0 || len(sc.Secure.KeyFile) == 0) {
-		return errors.New("a certificate and key file must be provided if https is enabled")
+	// Check that https is correctly configured: we need either a static certificate/key pair, ACME, or
+	// self-signed certificate generation.
+	if sc.Secure.Enabled {
+		switch {
+		case sc.Secure.ACME.Enabled:
+			if len(sc.Secure.ACME.Domains) == 0 {
+				return errors.New("acme is enabled but no domains were given")
+			}
+			if len(sc.Secure.ACME.CacheDir) == 0 {
+				return errors.New("acme is enabled but no cache_dir was given")
+			}
+		case sc.Secure.SelfSigned:
+			// No files required; we'll generate a certificate in memory at startup.
+		default:
+			if len(sc.Secure.CertificateFile) == 0 || len(sc.Secure.KeyFile) == 0 {
+				return errors.New("a certificate and key file must be provided if https is enabled")
+			}
+		}
 	}
 
 	if len(sc.DocumentRoot) != 0 {
@@ -156,8 +301,15 @@ func (sc *ServerConfig) Validate() error {
 		}
 	}
 
-	// Check that all our mapped directories are real.
+	// Check that all our mapped directories are real. Entries naming a registered Source are exempt: their
+	// FilePath isn't necessarily a path on this machine's disk at all (it might be a path inside a
+	// serving.EmbedSource, say, and a serving.ProxySource ignores it), so only the default disk-backed source can
+	// be validated this way.
 	for _, directory := range sc.Directories {
+		if len(directory.Source) > 0 {
+			continue
+		}
+
 		s, err := os.Stat(directory.FilePath)
 		if nil != err {
 			return errors.New(fmt.Sprintf("error reading served directory: %s", directory.FilePath))
@@ -168,6 +320,10 @@ func (sc *ServerConfig) Validate() error {
 	}
 
 	for _, file := range sc.Files {
+		if len(file.Source) > 0 {
+			continue
+		}
+
 		s, err := os.Stat(file.FilePath)
 		if nil != err {
 			return errors.New(fmt.Sprintf("error reading served file: %s", file.FilePath))
@@ -183,5 +339,21 @@ func (sc *ServerConfig) Validate() error {
 		}
 	}
 
+	switch strings.ToLower(sc.Logging.Format) {
+	case "", "common", "combined", "json":
+		// Valid, or left to the default.
+	default:
+		return errors.New(fmt.Sprintf("unknown logging format %s", sc.Logging.Format))
+	}
+
+	for _, mw := range sc.Middleware {
+		switch strings.ToLower(mw.Type) {
+		case "gzip", "cors", "basic-auth", "rate-limit":
+			// Valid.
+		default:
+			return errors.New(fmt.Sprintf("unknown middleware type %s", mw.Type))
+		}
+	}
+
 	return nil
 }
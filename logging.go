@@ -0,0 +1,301 @@
+package softserve
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// LogFormat identifies which access log line format a Server should emit.
+type LogFormat int
+
+const (
+	// LogFormatCommon emits access log lines in the Apache Common Log Format.
+	LogFormatCommon LogFormat = iota
+
+	// LogFormatCombined emits access log lines in the Apache Combined Log Format, which adds the referer and
+	// user-agent to the Common format.
+	LogFormatCombined
+
+	// LogFormatJSON emits one JSON object per request, suitable for structured log pipelines.
+	LogFormatJSON
+)
+
+// parseLogFormat converts the yaml "format" string into a LogFormat, defaulting to LogFormatCommon for anything
+// it doesn't recognize.
+func parseLogFormat(format string) LogFormat {
+
+	switch strings.ToLower(format) {
+	case "combined":
+		return LogFormatCombined
+	case "json":
+		return LogFormatJSON
+	default:
+		return LogFormatCommon
+	}
+
+}
+
+// SetAccessLogger installs w as the destination for access log lines, formatted according to format. Passing a
+// nil writer disables access logging.
+func (s *Server) SetAccessLogger(w io.Writer, format LogFormat) {
+
+	s.accessLogMu.Lock()
+	defer s.accessLogMu.Unlock()
+
+	s.accessLogger = w
+	s.logFormat = format
+
+}
+
+// responseRecorder wraps an http.ResponseWriter so the logging middleware can observe the status code and byte
+// count ultimately written to the client, neither of which http.ResponseWriter exposes on its own.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+
+	return n, err
+
+}
+
+// Flush delegates to the underlying ResponseWriter's http.Flusher, if it has one, so that streamed/chunked
+// responses (SSE, or a serving.ProxySource upstream) still flush correctly once wrapped for logging.
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack delegates to the underlying ResponseWriter's http.Hijacker, if it has one, so that a serving.ProxySource
+// upstream can still switch protocols (e.g. WebSocket) when access logging is wrapping it.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying ResponseWriter does not support hijacking")
+	}
+
+	return hijacker.Hijack()
+}
+
+// CloseNotify delegates to the underlying ResponseWriter's http.CloseNotifier, if it has one. It's deprecated in
+// net/http in favor of Request.Context, but httputil.ReverseProxy's older code paths still look for it.
+func (r *responseRecorder) CloseNotify() <-chan bool {
+	if notifier, ok := r.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+
+	closed := make(chan bool)
+	return closed
+}
+
+// loggingMiddleware wraps next so that every request handled through it is timed, measured, and recorded to the
+// Server's access logger. If no access logger has been configured, the wrapping is a no-op pass-through.
+func (s *Server) loggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
+
+	return func(response http.ResponseWriter, request *http.Request) {
+
+		s.accessLogMu.Lock()
+		logger := s.accessLogger
+		format := s.logFormat
+		s.accessLogMu.Unlock()
+
+		if logger == nil {
+			next(response, request)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: response}
+		start := time.Now()
+
+		next(recorder, request)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		writeAccessLogLine(logger, format, request, recorder.status, recorder.bytes, time.Since(start))
+
+	}
+
+}
+
+// writeAccessLogLine formats a single request/response pair according to format and writes it to w.
+func writeAccessLogLine(w io.Writer, format LogFormat, request *http.Request, status int, bytes int, duration time.Duration) {
+
+	remoteHost := request.RemoteAddr
+	if host, _, err := net.SplitHostPort(request.RemoteAddr); err == nil {
+		remoteHost = host
+	}
+
+	request_line := fmt.Sprintf("%s %s %s", request.Method, request.URL.RequestURI(), request.Proto)
+
+	switch format {
+	case LogFormatJSON:
+		fmt.Fprintf(w, `{"time":%q,"remote_addr":%q,"method":%q,"path":%q,"proto":%q,"status":%d,"bytes":%d,"duration_ms":%d,"referer":%q,"user_agent":%q}`+"\n",
+			time.Now().Format(time.RFC3339),
+			remoteHost,
+			request.Method,
+			request.URL.RequestURI(),
+			request.Proto,
+			status,
+			bytes,
+			duration.Milliseconds(),
+			request.Referer(),
+			request.UserAgent(),
+		)
+	case LogFormatCombined:
+		fmt.Fprintf(w, "%s - - [%s] %q %d %d %q %q\n",
+			remoteHost,
+			time.Now().Format("02/Jan/2006:15:04:05 -0700"),
+			request_line,
+			status,
+			bytes,
+			request.Referer(),
+			request.UserAgent(),
+		)
+	default:
+		fmt.Fprintf(w, "%s - - [%s] %q %d %d\n",
+			remoteHost,
+			time.Now().Format("02/Jan/2006:15:04:05 -0700"),
+			request_line,
+			status,
+			bytes,
+		)
+	}
+
+}
+
+// isStdStream returns whether a configured log target refers to one of the standard streams rather than a file.
+func isStdStream(target string) bool {
+	lower := strings.ToLower(target)
+	return lower == "stdout" || lower == "stderr"
+}
+
+// openLogDestination resolves a configured log target -- "stdout", "stderr", or a file path -- into a writable
+// io.Writer, opening (and creating, if necessary) the file in append mode.
+func openLogDestination(target string) (io.Writer, error) {
+
+	switch strings.ToLower(target) {
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	default:
+		return os.OpenFile(target, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	}
+
+}
+
+// initializeLogging sets up the access and error loggers described by the Server's LoggingConfig, opening any
+// configured log files and arranging for SIGHUP to reopen them for rotation.
+func (s *Server) initializeLogging() error {
+
+	conf := s.config.Logging
+
+	if len(conf.AccessLog) > 0 {
+		w, err := openLogDestination(conf.AccessLog)
+		if err != nil {
+			return fmt.Errorf("unable to open access log: %s", err.Error())
+		}
+
+		s.SetAccessLogger(w, parseLogFormat(conf.Format))
+		s.accessLogPath = conf.AccessLog
+	}
+
+	if len(conf.ErrorLog) > 0 {
+		w, err := openLogDestination(conf.ErrorLog)
+		if err != nil {
+			return fmt.Errorf("unable to open error log: %s", err.Error())
+		}
+
+		s.errorLogger = log.New(w, "", log.LstdFlags)
+		s.errorLogPath = conf.ErrorLog
+	}
+
+	if len(s.accessLogPath) > 0 || len(s.errorLogPath) > 0 {
+		s.watchLogRotation()
+	}
+
+	return nil
+
+}
+
+// logError writes a line to the configured error logger, falling back to the standard log package if no error
+// logger has been set up, so operational failures (a listener dying, a reload gone wrong) are never silently
+// dropped just because Logging.ErrorLog wasn't configured.
+func (s *Server) logError(format string, args ...any) {
+
+	if s.errorLogger != nil {
+		s.errorLogger.Printf(format, args...)
+		return
+	}
+
+	log.Printf(format, args...)
+
+}
+
+// logFatalError writes a line to the configured error logger and then terminates the process, the same way
+// log.Fatalf does, for failures serious enough that continuing to run doesn't make sense (e.g. a listener that
+// can no longer accept connections).
+func (s *Server) logFatalError(format string, args ...any) {
+
+	if s.errorLogger != nil {
+		s.errorLogger.Fatalf(format, args...)
+		return
+	}
+
+	log.Fatalf(format, args...)
+
+}
+
+// watchLogRotation installs a SIGHUP handler that reopens any file-backed access or error logs, so that an
+// external rotation tool (logrotate and friends) can rename the old file out from under us without losing data.
+func (s *Server) watchLogRotation() {
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		for range sigChan {
+
+			if len(s.accessLogPath) > 0 && !isStdStream(s.accessLogPath) {
+				if w, err := openLogDestination(s.accessLogPath); err == nil {
+					s.SetAccessLogger(w, s.logFormat)
+				}
+			}
+
+			if len(s.errorLogPath) > 0 && !isStdStream(s.errorLogPath) {
+				if w, err := openLogDestination(s.errorLogPath); err == nil {
+					s.errorLogger = log.New(w, "", log.LstdFlags)
+				}
+			}
+
+		}
+	}()
+
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuth returns a middleware enforcing HTTP basic authentication against users, a map of username to
+// bcrypt-hashed password. realm is sent back in the WWW-Authenticate challenge.
+func BasicAuth(realm string, users map[string]string) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+
+			user, pass, ok := request.BasicAuth()
+			if !ok || !validCredentials(users, user, pass) {
+				response.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+				http.Error(response, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(response, request)
+
+		})
+
+	}
+
+}
+
+// validCredentials checks user/pass against the bcrypt hash registered for user in users.
+func validCredentials(users map[string]string, user string, pass string) bool {
+
+	hash, ok := users[user]
+	if !ok {
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil
+
+}
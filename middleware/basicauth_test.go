@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidCredentials(t *testing.T) {
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unable to generate test hash: %s", err.Error())
+	}
+
+	users := map[string]string{"alice": string(hash)}
+
+	tests := []struct {
+		name string
+		user string
+		pass string
+		want bool
+	}{
+		{"correct password", "alice", "correct horse", true},
+		{"wrong password", "alice", "battery staple", false},
+		{"unknown user", "bob", "correct horse", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := validCredentials(users, tc.user, tc.pass); got != tc.want {
+				t.Errorf("validCredentials(%q, %q) = %v, want %v", tc.user, tc.pass, got, tc.want)
+			}
+		})
+	}
+
+}
+
+func TestBasicAuthMiddleware(t *testing.T) {
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unable to generate test hash: %s", err.Error())
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := BasicAuth("test realm", map[string]string{"alice": string(hash)})(next)
+
+	t.Run("no credentials", func(t *testing.T) {
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("got status %d, want %d", recorder.Code, http.StatusUnauthorized)
+		}
+
+		if got := recorder.Header().Get("WWW-Authenticate"); got != `Basic realm="test realm"` {
+			t.Errorf("WWW-Authenticate = %q, want %q", got, `Basic realm="test realm"`)
+		}
+
+	})
+
+	t.Run("wrong credentials", func(t *testing.T) {
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.SetBasicAuth("alice", "wrong password")
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("got status %d, want %d", recorder.Code, http.StatusUnauthorized)
+		}
+
+	})
+
+	t.Run("correct credentials", func(t *testing.T) {
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.SetBasicAuth("alice", "correct horse")
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", recorder.Code, http.StatusOK)
+		}
+
+	})
+
+}
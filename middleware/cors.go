@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the CORS middleware.
+type CORSOptions struct {
+
+	// AllowedOrigins is the set of origins allowed to make cross-origin requests. "*" allows any origin.
+	AllowedOrigins []string
+
+	// AllowedMethods is sent back as Access-Control-Allow-Methods on preflight requests.
+	AllowedMethods []string
+
+	// AllowedHeaders is sent back as Access-Control-Allow-Headers on preflight requests.
+	AllowedHeaders []string
+
+	// AllowCredentials, if true, sends Access-Control-Allow-Credentials: true.
+	AllowCredentials bool
+
+	// MaxAge, if positive, is sent back as Access-Control-Max-Age on preflight requests.
+	MaxAge time.Duration
+}
+
+// CORS returns a middleware which handles cross-origin requests according to opts, answering preflight
+// (OPTIONS) requests directly and adding the appropriate headers to everything else.
+func CORS(opts CORSOptions) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+
+			origin := request.Header.Get("Origin")
+			if len(origin) > 0 && originAllowed(opts.AllowedOrigins, origin) {
+				response.Header().Set("Access-Control-Allow-Origin", origin)
+				response.Header().Add("Vary", "Origin")
+
+				if opts.AllowCredentials {
+					response.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if request.Method == http.MethodOptions {
+				if len(opts.AllowedMethods) > 0 {
+					response.Header().Set("Access-Control-Allow-Methods", strings.Join(opts.AllowedMethods, ", "))
+				}
+
+				if len(opts.AllowedHeaders) > 0 {
+					response.Header().Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
+				}
+
+				if opts.MaxAge > 0 {
+					response.Header().Set("Access-Control-Max-Age", fmt.Sprintf("%d", int(opts.MaxAge.Seconds())))
+				}
+
+				response.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(response, request)
+
+		})
+
+	}
+
+}
+
+// originAllowed reports whether origin is permitted by allowed, which may contain "*" to match anything.
+func originAllowed(allowed []string, origin string) bool {
+
+	for _, candidate := range allowed {
+		if candidate == "*" || candidate == origin {
+			return true
+		}
+	}
+
+	return false
+
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOriginAllowed(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{"wildcard allows anything", []string{"*"}, "https://example.com", true},
+		{"exact match", []string{"https://example.com"}, "https://example.com", true},
+		{"no match", []string{"https://example.com"}, "https://evil.example.com", false},
+		{"empty allowlist", nil, "https://example.com", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := originAllowed(tc.allowed, tc.origin); got != tc.want {
+				t.Errorf("originAllowed(%v, %q) = %v, want %v", tc.allowed, tc.origin, got, tc.want)
+			}
+		})
+	}
+
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := CORS(CORSOptions{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true})(next)
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Origin", "https://example.com")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, "https://example.com")
+	}
+
+	if got := recorder.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, "true")
+	}
+
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := CORS(CORSOptions{AllowedOrigins: []string{"https://example.com"}})(next)
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Origin", "https://evil.example.com")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if got := recorder.Header().Get("Access-Control-Allow-Origin"); len(got) > 0 {
+		t.Errorf("Access-Control-Allow-Origin = %q, want unset", got)
+	}
+
+}
+
+func TestCORSPreflight(t *testing.T) {
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	handler := CORS(CORSOptions{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"X-Custom"},
+		MaxAge:         time.Hour,
+	})(next)
+
+	request := httptest.NewRequest(http.MethodOptions, "/", nil)
+	request.Header.Set("Origin", "https://example.com")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if called {
+		t.Error("preflight request should not reach the wrapped handler")
+	}
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("got status %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+
+	if got := recorder.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+		t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, "GET, POST")
+	}
+
+	if got := recorder.Header().Get("Access-Control-Allow-Headers"); got != "X-Custom" {
+		t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, "X-Custom")
+	}
+
+	if got := recorder.Header().Get("Access-Control-Max-Age"); got != "3600" {
+		t.Errorf("Access-Control-Max-Age = %q, want %q", got, "3600")
+	}
+
+}
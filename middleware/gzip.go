@@ -0,0 +1,94 @@
+// Package middleware ships a handful of ready-to-use http.Handler wrappers -- gzip compression, CORS, HTTP basic
+// auth, and per-IP rate limiting -- for use with Server.Use/Server.UseAt, or standalone in any net/http-based
+// project.
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Gzip returns a middleware that compresses responses with gzip at the given compression level (see
+// compress/gzip for valid values, e.g. gzip.DefaultCompression) whenever the client's Accept-Encoding header
+// advertises support for it.
+//
+// Any incoming Range/If-Range headers are stripped before the request reaches next: a byte range into the
+// uncompressed content doesn't correspond to any meaningful range of the gzip stream, and net/http's
+// http.ServeContent (used by DiskSource-backed handlers) would otherwise happily seek the uncompressed file to
+// that offset and gzip just the slice, producing a response that's neither a valid gzip stream nor the range the
+// client asked for. Stripping the headers makes Gzip always return the whole compressed body instead.
+func Gzip(level int) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+
+			if !strings.Contains(request.Header.Get("Accept-Encoding"), "gzip") {
+				next.ServeHTTP(response, request)
+				return
+			}
+
+			gz, err := gzip.NewWriterLevel(response, level)
+			if err != nil {
+				next.ServeHTTP(response, request)
+				return
+			}
+			defer gz.Close()
+
+			response.Header().Set("Content-Encoding", "gzip")
+			response.Header().Add("Vary", "Accept-Encoding")
+			response.Header().Del("Content-Length")
+
+			if len(request.Header.Get("Range")) > 0 || len(request.Header.Get("If-Range")) > 0 {
+				request = request.Clone(request.Context())
+				request.Header.Del("Range")
+				request.Header.Del("If-Range")
+			}
+
+			next.ServeHTTP(&gzipResponseWriter{ResponseWriter: response, writer: gz}, request)
+
+		})
+
+	}
+
+}
+
+// gzipResponseWriter wraps an http.ResponseWriter so that writes are transparently routed through a gzip.Writer.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	writer io.Writer
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	return w.writer.Write(b)
+}
+
+// Flush delegates to the underlying ResponseWriter's http.Flusher, if it has one, after flushing anything
+// buffered in the gzip.Writer, so streamed responses still make progress to the client as they're written.
+func (w *gzipResponseWriter) Flush() {
+
+	if gz, ok := w.writer.(*gzip.Writer); ok {
+		gz.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+}
+
+// Hijack delegates to the underlying ResponseWriter's http.Hijacker, if it has one, so a serving.ProxySource
+// upstream can still switch protocols (e.g. WebSocket) when gzip is wrapping it.
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying ResponseWriter does not support hijacking")
+	}
+
+	return hijacker.Hijack()
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucketTTL is how long an idle client's token bucket is kept before eviction. Without this, a client that
+// varies its key -- whether through legitimate IP churn, or deliberately, to dodge the limit -- would grow the
+// bucket map without bound.
+const bucketTTL = 10 * time.Minute
+
+// RateLimitOptions configures the rate-limiting middleware.
+type RateLimitOptions struct {
+
+	// RequestsPerSecond is the steady-state rate each client is allowed.
+	RequestsPerSecond float64
+
+	// Burst is how many requests a client can make in a single burst: the capacity of its token bucket.
+	Burst int
+
+	// TrustedProxies lists the CIDR ranges of reverse proxies allowed to set X-Forwarded-For. A request whose
+	// RemoteAddr doesn't match one of these is keyed by RemoteAddr itself, regardless of any
+	// X-Forwarded-For header it sends; otherwise any client could bypass the limit by sending a different
+	// value on every request. Leaving this empty means X-Forwarded-For is never trusted.
+	TrustedProxies []string
+}
+
+// RateLimit returns a middleware limiting each client to opts.RequestsPerSecond requests per second, with bursts
+// up to opts.Burst, using a token bucket keyed off the client's address. Clients over the limit receive a 429.
+func RateLimit(opts RateLimitOptions) func(http.Handler) http.Handler {
+
+	trusted := parseTrustedProxies(opts.TrustedProxies)
+
+	var mu sync.Mutex
+	buckets := make(map[string]*tokenBucket)
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+
+			key := clientKey(request, trusted)
+			now := time.Now()
+
+			mu.Lock()
+			b, ok := buckets[key]
+			if !ok {
+				b = &tokenBucket{tokens: float64(opts.Burst), lastRefill: now}
+				buckets[key] = b
+			}
+			evictStale(buckets, now)
+			mu.Unlock()
+
+			if !b.allow(opts.RequestsPerSecond, float64(opts.Burst), now) {
+				http.Error(response, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(response, request)
+
+		})
+
+	}
+
+}
+
+// evictStale removes buckets that haven't been touched in bucketTTL, so a client varying its key can't grow the
+// map forever. Callers must hold the map's mutex.
+func evictStale(buckets map[string]*tokenBucket, now time.Time) {
+
+	for key, b := range buckets {
+		b.mu.Lock()
+		stale := now.Sub(b.lastRefill) > bucketTTL
+		b.mu.Unlock()
+
+		if stale {
+			delete(buckets, key)
+		}
+	}
+
+}
+
+// tokenBucket is a simple per-client token bucket, refilled lazily on each request rather than via a background
+// goroutine, since that's all a rate limiter keyed by a potentially unbounded set of client addresses needs.
+type tokenBucket struct {
+	mu         sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+}
+
+func (b *tokenBucket) allow(rps float64, burst float64, now time.Time) bool {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += now.Sub(b.lastRefill).Seconds() * rps
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.lastRefill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+
+}
+
+// parseTrustedProxies turns the configured CIDR strings into net.IPNets, silently skipping any that don't
+// parse; an invalid entry should degrade to "don't trust X-Forwarded-For from anywhere", not fail the request.
+func parseTrustedProxies(cidrs []string) []*net.IPNet {
+
+	nets := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+
+	return nets
+
+}
+
+// remoteAddrTrusted reports whether remoteAddr (a RemoteAddr-style "host:port" or bare host) falls within one of
+// trusted.
+func remoteAddrTrusted(remoteAddr string, trusted []*net.IPNet) bool {
+
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// clientKey identifies the client a request should be rate-limited as. X-Forwarded-For (as set by an upstream
+// proxy/load balancer) is only honored when the request's RemoteAddr matches one of trusted; otherwise, or if no
+// trusted proxies are configured, RemoteAddr itself is used, since a direct client can put anything it likes in
+// that header.
+func clientKey(request *http.Request, trusted []*net.IPNet) string {
+
+	if len(trusted) > 0 && remoteAddrTrusted(request.RemoteAddr, trusted) {
+		if forwarded := request.Header.Get("X-Forwarded-For"); len(forwarded) > 0 {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+
+	return host
+
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientKey(t *testing.T) {
+
+	trusted := parseTrustedProxies([]string{"10.0.0.0/8"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		trusted    bool
+		want       string
+	}{
+		{
+			name:       "no proxy configured, XFF ignored",
+			remoteAddr: "203.0.113.5:1234",
+			forwarded:  "198.51.100.9",
+			trusted:    false,
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "untrusted remote address, XFF ignored",
+			remoteAddr: "203.0.113.5:1234",
+			forwarded:  "198.51.100.9",
+			trusted:    true,
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "trusted proxy, XFF honored",
+			remoteAddr: "10.1.2.3:1234",
+			forwarded:  "198.51.100.9, 10.1.2.3",
+			trusted:    true,
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "trusted proxy, no XFF header falls back to RemoteAddr",
+			remoteAddr: "10.1.2.3:1234",
+			forwarded:  "",
+			trusted:    true,
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "no port on RemoteAddr",
+			remoteAddr: "203.0.113.5",
+			forwarded:  "",
+			trusted:    false,
+			want:       "203.0.113.5",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = tc.remoteAddr
+			if len(tc.forwarded) > 0 {
+				request.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+
+			proxies := trusted
+			if !tc.trusted {
+				proxies = nil
+			}
+
+			if got := clientKey(request, proxies); got != tc.want {
+				t.Errorf("clientKey() = %q, want %q", got, tc.want)
+			}
+
+		})
+	}
+
+}
+
+func TestTokenBucketAllow(t *testing.T) {
+
+	start := time.Now()
+	b := &tokenBucket{tokens: 2, lastRefill: start}
+
+	if !b.allow(1, 2, start) {
+		t.Fatal("expected first request within burst to be allowed")
+	}
+
+	if !b.allow(1, 2, start) {
+		t.Fatal("expected second request within burst to be allowed")
+	}
+
+	if b.allow(1, 2, start) {
+		t.Fatal("expected third request to exceed burst and be denied")
+	}
+
+	later := start.Add(time.Second)
+	if !b.allow(1, 2, later) {
+		t.Fatal("expected a request one second later to be allowed after refilling a token")
+	}
+
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := RateLimit(RateLimitOptions{RequestsPerSecond: 1, Burst: 1})(next)
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "203.0.113.5:1234"
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, request)
+	if first.Code != http.StatusOK {
+		t.Fatalf("first request: got status %d, want %d", first.Code, http.StatusOK)
+	}
+
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, request)
+	if second.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: got status %d, want %d", second.Code, http.StatusTooManyRequests)
+	}
+
+}
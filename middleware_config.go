@@ -0,0 +1,73 @@
+package softserve
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Packetdancer/softserve/middleware"
+)
+
+// applyConfiguredMiddleware installs the middleware described by ServerConfig.Middleware, translating each
+// config-file entry into the corresponding middleware.* constructor and registering it via Use or UseAt.
+func (s *Server) applyConfiguredMiddleware() error {
+
+	for _, entry := range s.config.Middleware {
+		mw, err := buildMiddleware(entry)
+		if err != nil {
+			return err
+		}
+
+		if len(entry.PathPrefix) > 0 {
+			if err := s.UseAt(entry.PathPrefix, mw); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := s.Use(mw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// buildMiddleware translates a single MiddlewareConfig entry into the middleware constructor it describes.
+func buildMiddleware(entry MiddlewareConfig) (func(http.Handler) http.Handler, error) {
+
+	switch strings.ToLower(entry.Type) {
+	case "gzip":
+		level := entry.GzipLevel
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return middleware.Gzip(level), nil
+
+	case "cors":
+		return middleware.CORS(middleware.CORSOptions{
+			AllowedOrigins:   entry.CORS.AllowedOrigins,
+			AllowedMethods:   entry.CORS.AllowedMethods,
+			AllowedHeaders:   entry.CORS.AllowedHeaders,
+			AllowCredentials: entry.CORS.AllowCredentials,
+			MaxAge:           entry.CORS.MaxAge,
+		}), nil
+
+	case "basic-auth":
+		return middleware.BasicAuth(entry.BasicAuth.Realm, entry.BasicAuth.Users), nil
+
+	case "rate-limit":
+		return middleware.RateLimit(middleware.RateLimitOptions{
+			RequestsPerSecond: entry.RateLimit.RequestsPerSecond,
+			Burst:             entry.RateLimit.Burst,
+			TrustedProxies:    entry.RateLimit.TrustedProxies,
+		}), nil
+
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown middleware type %s", entry.Type))
+	}
+
+}
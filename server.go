@@ -17,13 +17,20 @@ package softserve
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/Packetdancer/softserve/serving"
 )
 
 // Server is the structure which holds all the state for a given SoftServe instance -- all the content providers,
@@ -45,6 +52,36 @@ type Server struct {
 	// handlers contains custom handlers we want to add when we start running the server
 	handlers map[string]http.HandlerFunc
 
+	// sources contains the named serving.Source implementations registered via RegisterSource, which
+	// Directories/Files entries can select via their source: yaml key.
+	sources map[string]serving.Source
+
+	// mux holds this Server's own *http.ServeMux, built during Finalize. Using a dedicated mux rather than
+	// http.DefaultServeMux is what lets more than one Server run in the same process. It's an atomic.Pointer,
+	// rather than a plain field, so Reload can swap in a freshly built mux while requests are in flight.
+	mux atomic.Pointer[http.ServeMux]
+
+	// configFilePath remembers the file a Server was configured from via ConfigureFromFile, so Reload knows
+	// what to re-read. Empty if the Server was configured directly via Configure instead.
+	configFilePath string
+
+	// configWatcher is the fsnotify watcher driving automatic reloads when ServerConfig.Watch is true.
+	configWatcher *fsnotify.Watcher
+
+	// reloadMu serializes the mux being built and swapped in, whether that happens via Finalize or a later
+	// Reload, so the two can never race to Store a half-built *http.ServeMux.
+	reloadMu sync.Mutex
+
+	// secureTLSConfig is the *tls.Config the https server should use, computed once by configureTLS during
+	// Finalize. It stays nil unless ACME or a self-signed certificate is configured.
+	secureTLSConfig *tls.Config
+
+	// acmeChallengeHandler serves ACME's HTTP-01 challenge when ACME is enabled, computed once by
+	// configureTLS during Finalize and registered into every mux buildMux produces. It must not be registered
+	// a second time from Start, or restarting the server (Stop then Start again) panics with a "multiple
+	// registrations" error against the same underlying *http.ServeMux.
+	acmeChallengeHandler http.Handler
+
 	// Our underlying net/http server implementation for our http server
 	server *http.Server
 
@@ -53,6 +90,47 @@ type Server struct {
 
 	// Our waitGroup to keep the goroutines in sync
 	waitGroup *sync.WaitGroup
+
+	// accessLogMu guards accessLogger and logFormat, since log rotation can swap them in from a signal handler
+	// goroutine while requests are being served.
+	accessLogMu sync.Mutex
+
+	// accessLogger is where access log lines are written, if access logging has been configured.
+	accessLogger io.Writer
+
+	// logFormat determines how access log lines are formatted.
+	logFormat LogFormat
+
+	// accessLogPath remembers the configured access log file path, if any, so SIGHUP can reopen it for rotation.
+	accessLogPath string
+
+	// errorLogger is where error log lines are written, if error logging has been configured.
+	errorLogger *log.Logger
+
+	// errorLogPath remembers the configured error log file path, if any, so SIGHUP can reopen it for rotation.
+	errorLogPath string
+
+	// drainMu guards draining, since it is flipped by Stop but read from every in-flight request's goroutine.
+	drainMu sync.Mutex
+
+	// draining is true once Stop has begun a lame-duck period; requests arriving while it's true are rejected
+	// with a 503 rather than handed to the real handler.
+	draining bool
+
+	// globalMiddleware contains middleware registered via Use, applied to every handler Finalize wires up. The
+	// first entry registered is the outermost wrapper.
+	globalMiddleware []func(http.Handler) http.Handler
+
+	// pathMiddleware contains middleware registered via UseAt, in registration order. It's a slice rather
+	// than a map keyed by prefix so that when a request path matches more than one prefix, the middleware is
+	// applied in the deterministic order UseAt was called, not Go's randomized map iteration order.
+	pathMiddleware []pathMiddlewareEntry
+}
+
+// pathMiddlewareEntry pairs a path prefix registered via UseAt with the middleware scoped to it.
+type pathMiddlewareEntry struct {
+	prefix     string
+	middleware []func(http.Handler) http.Handler
 }
 
 // Configure attempts to set up a server using the provided ServerConfig. This function must only ever be called
@@ -78,6 +156,65 @@ func (s *Server) Configure(conf ServerConfig) error {
 	s.secureServer = nil
 
 	s.handlers = make(map[string]http.HandlerFunc, 0)
+	s.sources = make(map[string]serving.Source, 0)
+	s.globalMiddleware = make([]func(http.Handler) http.Handler, 0)
+	s.pathMiddleware = make([]pathMiddlewareEntry, 0)
+
+	return nil
+}
+
+// Use registers a global middleware, wrapping every handler Finalize wires up -- custom handlers, redirects,
+// files, directories, and the DocumentRoot fallback alike. Middleware registered first wraps outermost, so it
+// sees a request before (and a response after) middleware registered afterward.
+func (s *Server) Use(mw func(http.Handler) http.Handler) error {
+
+	if s.finalized {
+		return errors.New("server configuration has been finalized; too late to add middleware now")
+	}
+
+	s.globalMiddleware = append(s.globalMiddleware, mw)
+
+	return nil
+}
+
+// UseAt registers middleware scoped to handlers registered under pathPrefix. It runs inside any global
+// middleware from Use, in the order given.
+func (s *Server) UseAt(pathPrefix string, mw ...func(http.Handler) http.Handler) error {
+
+	if s.finalized {
+		return errors.New("server configuration has been finalized; too late to add middleware now")
+	}
+
+	for i := range s.pathMiddleware {
+		if s.pathMiddleware[i].prefix == pathPrefix {
+			s.pathMiddleware[i].middleware = append(s.pathMiddleware[i].middleware, mw...)
+			return nil
+		}
+	}
+
+	s.pathMiddleware = append(s.pathMiddleware, pathMiddlewareEntry{prefix: pathPrefix, middleware: mw})
+
+	return nil
+}
+
+// RegisterSource adds a named content serving.Source that Directories/Files entries can select via their
+// source: yaml key. Built-in disk serving doesn't need to be registered explicitly; RegisterSource is how you
+// wire up a serving.EmbedSource, serving.ProxySource, or a custom implementation.
+func (s *Server) RegisterSource(name string, src serving.Source) error {
+
+	if s.running {
+		return errors.New("server is already running; sources can only be added when stopped")
+	}
+
+	if s.finalized {
+		return errors.New("server configuration has been finalized; too late to register a source now")
+	}
+
+	if _, ok := s.sources[name]; ok {
+		return errors.New("a source is already registered under that name")
+	}
+
+	s.sources[name] = src
 
 	return nil
 }
@@ -95,38 +232,152 @@ func (s *Server) Finalize() error {
 		return errors.New(fmt.Sprintf("configuration error: %s", err.Error()))
 	}
 
+	// Set up our access/error loggers before we start wrapping handlers with them.
+	if err := s.initializeLogging(); err != nil {
+		return err
+	}
+
+	// Install any middleware described by ServerConfig.Middleware, alongside whatever was registered directly
+	// via Use/UseAt.
+	if err := s.applyConfiguredMiddleware(); err != nil {
+		return err
+	}
+
+	// Work out our TLS configuration, if any, before building the mux, so that buildMux can register the
+	// ACME HTTP-01 challenge handler (if ACME is enabled) once and for all.
+	if s.config.Secure.Enabled {
+		var err error
+		s.secureTLSConfig, s.acmeChallengeHandler, err = s.configureTLS()
+		if err != nil {
+			return err
+		}
+
+		// ACME's HTTP-01 challenge must be served in the clear; auto-enable the basic server on :80 if the
+		// caller hasn't already turned it on.
+		if s.acmeChallengeHandler != nil && !s.config.Basic.Enabled {
+			s.config.Basic.Enabled = true
+			s.config.Basic.Port = 80
+		}
+	}
+
 	s.finalized = true
 
+	s.reloadMu.Lock()
+	mux, err := s.buildMux()
+	if err == nil {
+		s.mux.Store(mux)
+	}
+	s.reloadMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	// If we were configured from a file and asked to watch it, start doing so now that there's a mux in
+	// place for Reload to swap.
+	if s.config.Watch && len(s.configFilePath) > 0 {
+		if err := s.watchConfigFiles(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// buildMux constructs a fresh *http.ServeMux wiring up every custom handler, redirect, file, directory, and the
+// DocumentRoot fallback described by the Server's current configuration, with the standard middleware stack
+// applied to each. Finalize calls this once; Reload calls it again each time it runs.
+func (s *Server) buildMux() (*http.ServeMux, error) {
+
+	mux := http.NewServeMux()
+
 	// Configure our handlers. First we go with any custom handlers...
 	for path, handlerFunc := range s.handlers {
-		http.Handle(path, handlerFunc)
+		mux.HandleFunc(path, s.wrapHandler(path, handlerFunc))
 	}
 
 	// Then any redirects...
 	for _, redirect := range s.config.Redirects {
-		http.Handle(redirect.OldPath, http.RedirectHandler(redirect.NewPath, redirect.Code))
+		mux.Handle(redirect.OldPath, s.wrapHandler(redirect.OldPath, http.RedirectHandler(redirect.NewPath, redirect.Code).ServeHTTP))
 	}
 
 	// Then any specific files...
 	for _, record := range s.config.Files {
-		handlerFunc, err := s.serveDocumentFunction(record.FilePath, record.ContentType)
+		handlerFunc, err := s.serveDocumentFunction(record)
 		if err != nil {
-			return errors.New(fmt.Sprintf("setup failure: %s", err.Error()))
+			return nil, errors.New(fmt.Sprintf("setup failure: %s", err.Error()))
 		}
-		http.HandleFunc(record.WebPath, handlerFunc)
+		mux.HandleFunc(record.WebPath, s.wrapHandler(record.WebPath, handlerFunc))
 	}
 
 	// Then any directories...
 	for _, record := range s.config.Directories {
-		http.Handle(record.WebPath, http.FileServer(http.Dir(record.FilePath)))
+		handlerFunc, err := s.serveDirectoryFunction(record)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("setup failure: %s", err.Error()))
+		}
+		mux.HandleFunc(record.WebPath, s.wrapHandler(record.WebPath, handlerFunc))
 	}
 
 	// And lastly our fallback
 	if len(s.config.DocumentRoot) > 0 {
-		http.HandleFunc("/", s.serveDocumentRoot)
+		mux.HandleFunc("/", s.wrapHandler("/", s.serveDocumentRoot))
 	}
 
-	return nil
+	// If ACME is enabled, its HTTP-01 challenge handler goes on every mux we build, so it's in place from
+	// the very first Start and survives any later Reload.
+	if s.acmeChallengeHandler != nil {
+		mux.Handle("/.well-known/acme-challenge/", s.acmeChallengeHandler)
+	}
+
+	return mux, nil
+
+}
+
+// dispatch is the thin http.Handler installed on both net/http servers. It forwards to whichever *http.ServeMux
+// is current, so Reload can swap in a freshly built one without restarting the listeners or dropping connections.
+func (s *Server) dispatch(response http.ResponseWriter, request *http.Request) {
+	s.mux.Load().ServeHTTP(response, request)
+}
+
+// Handler returns the Server's underlying http.Handler, so that it can be mounted inside another router (chi,
+// gorilla/mux, etc.) or driven directly by httptest.NewServer for unit tests, instead of having Start bind real
+// listeners. It calls Finalize first if that hasn't happened yet.
+func (s *Server) Handler() (http.Handler, error) {
+
+	if !s.finalized {
+		if err := s.Finalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	return http.HandlerFunc(s.dispatch), nil
+
+}
+
+// wrapHandler applies the standard stack of Server-level middleware around a handler being registered under
+// path during Finalize: any path-scoped UseAt middleware whose prefix matches path, then any global Use
+// middleware, then our own built-in draining and access logging.
+func (s *Server) wrapHandler(path string, handlerFunc http.HandlerFunc) http.HandlerFunc {
+
+	var handler http.Handler = handlerFunc
+
+	for _, entry := range s.pathMiddleware {
+		if !strings.HasPrefix(path, entry.prefix) {
+			continue
+		}
+
+		for i := len(entry.middleware) - 1; i >= 0; i-- {
+			handler = entry.middleware[i](handler)
+		}
+	}
+
+	for i := len(s.globalMiddleware) - 1; i >= 0; i-- {
+		handler = s.globalMiddleware[i](handler)
+	}
+
+	return s.loggingMiddleware(s.drainMiddleware(handler.ServeHTTP))
 
 }
 
@@ -177,138 +428,6 @@ func (s *Server) RegisterHandler(path string, handler http.HandlerFunc) error {
 	return nil
 }
 
-// serveDocumentFunction is the internal function which handles serving a specific file at a specific path.
-func (s *Server) serveDocumentFunction(filePath string, contentType string) (http.HandlerFunc, error) {
-
-	fileSize := 0
-
-	// Check that this file exists.
-	{
-		fileInfo, err := os.Stat(filePath)
-		if err != nil {
-			return nil, errors.New(fmt.Sprintf("unable to serve document %s: %s", filePath, err.Error()))
-		}
-
-		if fileInfo.Size() == 0 {
-			return nil, errors.New(fmt.Sprintf("unable to serve document %s: zero length file", filePath))
-		}
-
-		fileSize = int(fileInfo.Size())
-	}
-
-	if len(contentType) == 0 {
-		ct, err := GetContentType(filePath)
-		if err != nil {
-			ct = "application/octet-stream"
-		}
-
-		contentType = ct
-	}
-
-	buffer := make([]byte, 0)
-
-	{
-		fp, err := os.Open(filePath)
-		if err != nil {
-			return nil, errors.New(fmt.Sprintf("could not open file %s for serving: %s", filePath, err.Error()))
-		}
-		defer fp.Close()
-
-		buffer = make([]byte, fileSize)
-		_, err = fp.Read(buffer)
-	}
-
-	// Build a function that returns this static file.
-	return func(response http.ResponseWriter, request *http.Request) {
-
-		response.Header().Set("Content-Type", contentType)
-		response.Header().Set("Content-Length", fmt.Sprintf("%d", fileSize))
-		response.WriteHeader(200)
-		response.Write(buffer)
-
-	}, nil
-
-}
-
-// serveDocumentRoot is our baseline handler for the DocumentRoot, if provided.
-func (s *Server) serveDocumentRoot(response http.ResponseWriter, request *http.Request) {
-
-	path := filepath.Clean(request.URL.Path)
-	if path[len(path)-1:] == "/" {
-		path = path + "index.html"
-	}
-
-	filePath := filepath.Join(s.config.DocumentRoot, path)
-	fileSize := 0
-
-	// Check our file information.
-	{
-		fileInfo, err := os.Stat(filePath)
-		if err != nil {
-			// If the file is not found, return a 404.
-			if os.IsNotExist(err) {
-				http.NotFound(response, request)
-				return
-			}
-
-			// Generic 500 "internal error"
-			http.Error(response, http.StatusText(500), 500)
-			return
-		}
-
-		if fileInfo.IsDir() {
-			// We're meant to be a very basic webserver for writing simple webservices,
-			// not a replacement for Apache! Directory indexes is a bit beyond what we want.
-			http.NotFound(response, request)
-			return
-		}
-
-		// If it's a zero length file, treat it as not found.
-		if fileInfo.Size() == 0 {
-			http.NotFound(response, request)
-			return
-		}
-
-		fileSize = int(fileInfo.Size())
-	}
-
-	contentType := "application/octet-stream"
-
-	{
-		fileType, err := GetContentType(filePath)
-		if err != nil {
-			// Generic "something went wrong" error.
-			http.Error(response, http.StatusText(500), 500)
-			return
-		}
-
-		contentType = fileType
-	}
-
-	buffer := make([]byte, fileSize)
-
-	{
-		fp, err := os.Open(filePath)
-		if err != nil {
-			http.Error(response, http.StatusText(500), 500)
-			return
-		}
-		defer fp.Close()
-
-		readSize, err2 := fp.Read(buffer)
-		if err2 != nil || readSize != fileSize {
-			http.Error(response, http.StatusText(500), 500)
-			return
-		}
-	}
-
-	response.Header().Set("Content-Type", contentType)
-	response.Header().Set("Content-Length", fmt.Sprintf("%d", fileSize))
-	response.WriteHeader(200)
-	response.Write(buffer)
-
-}
-
 // Start will set a SoftServe instance running, and begin serving pages on the appropriate ports. If Finalize has not
 // been called before Start, it will be implicitly called as part of startup.
 func (s *Server) Start() error {
@@ -330,7 +449,7 @@ func (s *Server) Start() error {
 
 	// Configure our basic webserver, if we're going to use it.
 	if s.config.Basic.Enabled {
-		s.server = &http.Server{Addr: fmt.Sprintf(":%d", s.config.Basic.Port)}
+		s.server = &http.Server{Addr: fmt.Sprintf(":%d", s.config.Basic.Port), Handler: http.HandlerFunc(s.dispatch)}
 
 		s.waitGroup.Add(1)
 
@@ -339,14 +458,20 @@ func (s *Server) Start() error {
 			defer s.waitGroup.Done()
 
 			if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
-				log.Fatalf("fatal http server error: %s", err.Error())
+				s.logFatalError("fatal http server error: %s", err.Error())
 			}
 		}()
 	}
 
 	// Configure our secure webserver, if we're going to use it.
 	if s.config.Secure.Enabled {
-		s.secureServer = &http.Server{Addr: fmt.Sprintf(":%d", s.config.Secure.Port)}
+		s.secureServer = &http.Server{Addr: fmt.Sprintf(":%d", s.config.Secure.Port), Handler: http.HandlerFunc(s.dispatch)}
+
+		certFile, keyFile := s.config.Secure.CertificateFile, s.config.Secure.KeyFile
+		if s.secureTLSConfig != nil {
+			s.secureServer.TLSConfig = s.secureTLSConfig
+			certFile, keyFile = "", ""
+		}
 
 		s.waitGroup.Add(1)
 
@@ -354,8 +479,8 @@ func (s *Server) Start() error {
 		go func() {
 			defer s.waitGroup.Done()
 
-			if err := s.secureServer.ListenAndServeTLS(s.config.Secure.CertificateFile, s.config.Secure.KeyFile); err != http.ErrServerClosed {
-				log.Fatalf("fatal https server error: %s", err.Error())
+			if err := s.secureServer.ListenAndServeTLS(certFile, keyFile); err != http.ErrServerClosed {
+				s.logFatalError("fatal https server error: %s", err.Error())
 			}
 		}()
 	}
@@ -373,40 +498,84 @@ func (s *Server) Start() error {
 
 }
 
-// Stop will shut down a SoftServe instance. If the blocking parameter is true, it will not return from this call
-// until the server has stopped; otherwise, it will return immediately and perform the shutdown in the background.
-func (s *Server) Stop(blocking bool) error {
+// StopContext performs a graceful shutdown of a SoftServe instance. It first flips the server into a draining
+// state, where new requests receive a 503 with Connection: close, holds that lame-duck state for
+// ServerConfig.LameDuckPeriod (to give load balancers time to stop routing here), and then shuts down both the
+// http and https servers concurrently, bounding that shutdown with both ctx and ServerConfig.ShutdownTimeout.
+// The lame-duck period itself always runs in full; neither ctx nor ShutdownTimeout cuts it short.
+func (s *Server) StopContext(ctx context.Context) error {
 
 	if !s.IsRunning() {
 		// Just exit. It's not worth giving an error that it wasn't running.
 		return nil
 	}
 
+	s.setDraining(true)
+	defer s.setDraining(false)
+
+	if s.config.LameDuckPeriod > 0 {
+		time.Sleep(s.config.LameDuckPeriod)
+	}
+
+	shutdownCtx := ctx
+	if s.config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(ctx, s.config.ShutdownTimeout)
+		defer cancel()
+	}
+
+	var shutdownErrMu sync.Mutex
+	var shutdownErr error
+	var wg sync.WaitGroup
+
 	if s.server != nil {
-		// TODO: Maybe improve code to use real context? This is a super simple webservice framework, though...
-		err := s.server.Shutdown(context.TODO())
-		if err != nil {
-			return err
-		}
-		s.server = nil
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := s.server.Shutdown(shutdownCtx); err != nil {
+				shutdownErrMu.Lock()
+				shutdownErr = err
+				shutdownErrMu.Unlock()
+			}
+			s.server = nil
+		}()
 	}
 
 	if s.secureServer != nil {
-		// TODO: Maybe improve code to use real context? This is a super simple webservice framework, though...
-		err := s.secureServer.Shutdown(context.TODO())
-		if err != nil {
-			return err
-		}
-		s.secureServer = nil
-	}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-	if blocking {
-		s.waitGroup.Wait()
+			if err := s.secureServer.Shutdown(shutdownCtx); err != nil {
+				shutdownErrMu.Lock()
+				shutdownErr = err
+				shutdownErrMu.Unlock()
+			}
+			s.secureServer = nil
+		}()
 	}
 
+	wg.Wait()
+	s.waitGroup.Wait()
+
 	s.running = false
 
-	return nil
+	return shutdownErr
+
+}
+
+// Stop is a thin wrapper around StopContext kept for backwards compatibility with older callers. If blocking is
+// true, it behaves like StopContext(context.Background()); if false, it triggers the same shutdown in the
+// background and returns immediately.
+func (s *Server) Stop(blocking bool) error {
+
+	if !blocking {
+		go s.StopContext(context.Background())
+		return nil
+	}
+
+	return s.StopContext(context.Background())
 
 }
 
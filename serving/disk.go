@@ -0,0 +1,61 @@
+package serving
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// DiskSource serves content directly from local disk. It is the default Source used when a DiskRecord doesn't
+// name a registered source.
+type DiskSource struct {
+
+	// Root is joined onto every path passed to Open/ContentType. It may be empty, in which case paths are
+	// used as given.
+	Root string
+}
+
+// NewDiskSource creates a DiskSource rooted at root.
+func NewDiskSource(root string) *DiskSource {
+	return &DiskSource{Root: root}
+}
+
+// Open implements Source by opening the file at filepath.Join(d.Root, path) from local disk.
+func (d *DiskSource) Open(path string) (io.ReadSeeker, os.FileInfo, error) {
+
+	fullPath := filepath.Join(d.Root, path)
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fp, err := os.Open(fullPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fp, info, nil
+
+}
+
+// ContentType implements Source by sniffing the first 512 bytes of the file at filepath.Join(d.Root, path), the
+// same technique used by http.DetectContentType.
+func (d *DiskSource) ContentType(path string) string {
+
+	fp, err := os.Open(filepath.Join(d.Root, path))
+	if err != nil {
+		return ""
+	}
+	defer fp.Close()
+
+	buf := make([]byte, 512)
+	n, err := fp.Read(buf)
+	if err != nil && err != io.EOF {
+		return ""
+	}
+
+	return http.DetectContentType(buf[:n])
+
+}
@@ -0,0 +1,83 @@
+package serving
+
+import (
+	"bytes"
+	"embed"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// EmbedSource serves content out of an embed.FS, letting a consumer ship static assets inside a single binary
+// instead of needing them present on disk at runtime.
+type EmbedSource struct {
+
+	// FS is the embedded filesystem content is served from.
+	FS embed.FS
+
+	// Root is an optional prefix within FS to join onto every path, e.g. "static" if assets were embedded via
+	// `//go:embed static`.
+	Root string
+}
+
+// NewEmbedSource creates an EmbedSource serving out of fsys, joining every path onto root.
+func NewEmbedSource(fsys embed.FS, root string) *EmbedSource {
+	return &EmbedSource{FS: fsys, Root: root}
+}
+
+// resolve joins path onto e.Root, the way embed.FS expects: forward-slash separated, with no leading slash.
+func (e *EmbedSource) resolve(path string) string {
+
+	path = strings.TrimPrefix(path, "/")
+
+	if len(e.Root) == 0 {
+		return path
+	}
+
+	return strings.TrimSuffix(e.Root, "/") + "/" + path
+
+}
+
+// Open implements Source. Since fs.File doesn't generally support Seek, the full content is read into memory and
+// wrapped in a bytes.Reader.
+func (e *EmbedSource) Open(path string) (io.ReadSeeker, os.FileInfo, error) {
+
+	f, err := e.FS.Open(e.resolve(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bytes.NewReader(data), info, nil
+
+}
+
+// ContentType implements Source by sniffing the first 512 bytes of the embedded file at path.
+func (e *EmbedSource) ContentType(path string) string {
+
+	f, err := e.FS.Open(e.resolve(path))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := f.Read(buf)
+	if err != nil && err != io.EOF {
+		return ""
+	}
+
+	return http.DetectContentType(buf[:n])
+
+}
@@ -0,0 +1,37 @@
+package serving
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+)
+
+// ProxySource reverse-proxies requests to an upstream URL instead of serving local content. Because a proxied
+// response isn't a seekable file, Open always returns an error; a Server recognizes a ProxySource and calls
+// ServeHTTP directly rather than going through Open/ContentType.
+type ProxySource struct {
+	proxy *httputil.ReverseProxy
+}
+
+// NewProxySource creates a ProxySource which forwards requests on to upstream.
+func NewProxySource(upstream *url.URL) *ProxySource {
+	return &ProxySource{proxy: httputil.NewSingleHostReverseProxy(upstream)}
+}
+
+// Open always fails; ProxySource content isn't file-like. It exists to satisfy the Source interface.
+func (p *ProxySource) Open(path string) (io.ReadSeeker, os.FileInfo, error) {
+	return nil, nil, errors.New("ProxySource does not support Open; requests must be dispatched via ServeHTTP")
+}
+
+// ContentType always returns an empty string; the upstream response sets its own Content-Type.
+func (p *ProxySource) ContentType(path string) string {
+	return ""
+}
+
+// ServeHTTP forwards the request to the configured upstream.
+func (p *ProxySource) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	p.proxy.ServeHTTP(response, request)
+}
@@ -0,0 +1,22 @@
+// Package serving defines Source, the abstraction softserve uses for where the content it serves actually comes
+// from. A Server normally serves straight off local disk, but some deployments want to ship static assets inside
+// a single binary via embed.FS, or hand a path off to an upstream service instead; Source lets those backends
+// plug into the same Directories/Files configuration rather than requiring a different code path each.
+package serving
+
+import (
+	"io"
+	"os"
+)
+
+// Source is implemented by anything that can serve content for a given request path. Built-in implementations
+// are DiskSource, EmbedSource, and ProxySource.
+type Source interface {
+
+	// Open returns a ReadSeeker for the content at path, along with its os.FileInfo, or an error if it could
+	// not be opened. Callers should close the returned ReadSeeker if it implements io.Closer.
+	Open(path string) (io.ReadSeeker, os.FileInfo, error)
+
+	// ContentType returns the MIME type that should be used when serving path.
+	ContentType(path string) string
+}
@@ -0,0 +1,66 @@
+package softserve
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+)
+
+// isDraining reports whether the server is presently in the lame-duck period of a graceful Stop.
+func (s *Server) isDraining() bool {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+
+	return s.draining
+}
+
+// setDraining flips the server's draining state.
+func (s *Server) setDraining(draining bool) {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+
+	s.draining = draining
+}
+
+// drainMiddleware wraps next so that requests arriving while the server is draining (see Stop) are rejected with
+// a 503 and Connection: close, rather than being handed to the real handler.
+func (s *Server) drainMiddleware(next http.HandlerFunc) http.HandlerFunc {
+
+	return func(response http.ResponseWriter, request *http.Request) {
+
+		if s.isDraining() {
+			response.Header().Set("Connection", "close")
+			http.Error(response, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		next(response, request)
+
+	}
+
+}
+
+// HandleSignals installs a signal handler that triggers StopContext when any of sigs is received, so that
+// callers don't have to wire up their own signal.Notify plumbing just to shut down cleanly. If no signals are
+// given, it defaults to os.Interrupt. The triggered shutdown runs with a background context, bounded by
+// ServerConfig.ShutdownTimeout as usual.
+func (s *Server) HandleSignals(sigs ...os.Signal) {
+
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt}
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, sigs...)
+
+	go func() {
+		<-sigChan
+
+		if err := s.StopContext(context.Background()); err != nil {
+			log.Printf("error during signal-triggered shutdown: %s", err.Error())
+		}
+	}()
+
+}
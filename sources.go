@@ -0,0 +1,176 @@
+package softserve
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Packetdancer/softserve/serving"
+)
+
+// resolveSource returns the serving.Source that should handle record, along with the source-relative path to
+// pass to its Open/ContentType methods. If record.Source names a registered source, that source is used with
+// record.FilePath as the relative path; otherwise a plain serving.DiskSource is used directly against
+// record.FilePath.
+func (s *Server) resolveSource(record DiskRecord) (serving.Source, string, error) {
+
+	if len(record.Source) == 0 {
+		return serving.NewDiskSource(""), record.FilePath, nil
+	}
+
+	src, ok := s.sources[record.Source]
+	if !ok {
+		return nil, "", errors.New(fmt.Sprintf("no source registered under the name %s", record.Source))
+	}
+
+	return src, record.FilePath, nil
+
+}
+
+// serveDocumentFunction builds the handler which serves a single configured file, resolved through the record's
+// content Source (disk by default) and handed to http.ServeContent, so Range requests, ETags, and
+// If-Modified-Since are honored instead of a single buffered read at startup that's never revalidated.
+func (s *Server) serveDocumentFunction(record DiskRecord) (http.HandlerFunc, error) {
+
+	src, path, err := s.resolveSource(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxy, ok := src.(*serving.ProxySource); ok {
+		return proxy.ServeHTTP, nil
+	}
+
+	// Confirm the file actually exists up front, so misconfiguration is caught at Finalize time rather than on
+	// the first request.
+	if reader, _, err := src.Open(path); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to serve document %s: %s", path, err.Error()))
+	} else if closer, ok := reader.(io.Closer); ok {
+		closer.Close()
+	}
+
+	contentType := record.ContentType
+
+	return func(response http.ResponseWriter, request *http.Request) {
+
+		reader, info, err := src.Open(path)
+		if err != nil {
+			http.NotFound(response, request)
+			return
+		}
+
+		if closer, ok := reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		if len(contentType) > 0 {
+			response.Header().Set("Content-Type", contentType)
+		} else if ct := src.ContentType(path); len(ct) > 0 {
+			response.Header().Set("Content-Type", ct)
+		}
+
+		http.ServeContent(response, request, info.Name(), info.ModTime(), reader)
+
+	}, nil
+
+}
+
+// serveDirectoryFunction builds the handler which serves a directory mount, resolved through the record's content
+// Source (disk by default). It strips the mount's registered WebPath prefix before resolving the remainder of
+// the request path against the source.
+func (s *Server) serveDirectoryFunction(record DiskRecord) (http.HandlerFunc, error) {
+
+	src, root, err := s.resolveSource(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxy, ok := src.(*serving.ProxySource); ok {
+		return proxy.ServeHTTP, nil
+	}
+
+	prefix := record.WebPath
+
+	return func(response http.ResponseWriter, request *http.Request) {
+
+		subPath := strings.TrimPrefix(request.URL.Path, prefix)
+		path := filepath.Join(root, subPath)
+
+		reader, info, err := src.Open(path)
+		if err != nil {
+			http.NotFound(response, request)
+			return
+		}
+
+		if closer, ok := reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		if info.IsDir() {
+			// We're meant to be a very basic webserver for writing simple webservices, not a replacement
+			// for Apache! Directory indexes is a bit beyond what we want.
+			http.NotFound(response, request)
+			return
+		}
+
+		if len(record.ContentType) > 0 {
+			response.Header().Set("Content-Type", record.ContentType)
+		} else if ct := src.ContentType(path); len(ct) > 0 {
+			response.Header().Set("Content-Type", ct)
+		}
+
+		http.ServeContent(response, request, info.Name(), info.ModTime(), reader)
+
+	}, nil
+
+}
+
+// serveDocumentRoot is our baseline handler for the DocumentRoot, if provided. It serves straight off disk
+// through http.ServeContent, so DocumentRoot responses get Range, ETag, and If-Modified-Since support just like
+// named file and directory mounts do.
+func (s *Server) serveDocumentRoot(response http.ResponseWriter, request *http.Request) {
+
+	path := filepath.Clean(request.URL.Path)
+	if path[len(path)-1:] == "/" {
+		path = path + "index.html"
+	}
+
+	src := serving.NewDiskSource(s.config.DocumentRoot)
+
+	reader, info, err := src.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.NotFound(response, request)
+		} else {
+			http.Error(response, http.StatusText(500), 500)
+		}
+		return
+	}
+
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	if info.IsDir() {
+		// We're meant to be a very basic webserver for writing simple webservices, not a replacement for
+		// Apache! Directory indexes is a bit beyond what we want.
+		http.NotFound(response, request)
+		return
+	}
+
+	if info.Size() == 0 {
+		http.NotFound(response, request)
+		return
+	}
+
+	if ct := src.ContentType(path); len(ct) > 0 {
+		response.Header().Set("Content-Type", ct)
+	}
+
+	http.ServeContent(response, request, info.Name(), info.ModTime(), reader)
+
+}
@@ -0,0 +1,94 @@
+package softserve
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// configureTLS builds the *tls.Config the secure server should use, based on the Secure block of the server's
+// configuration. If ACME is enabled, it also returns the HTTP-01 challenge handler that must be mounted on the
+// basic (non-TLS) server; otherwise the returned handler is nil.
+func (s *Server) configureTLS() (*tls.Config, http.Handler, error) {
+
+	secure := s.config.Secure
+
+	switch {
+	case secure.ACME.Enabled:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(secure.ACME.Domains...),
+			Cache:      autocert.DirCache(secure.ACME.CacheDir),
+			Email:      secure.ACME.Email,
+		}
+
+		if secure.ACME.Staging {
+			manager.Client = &acme.Client{DirectoryURL: "https://acme-staging-v02.api.letsencrypt.org/directory"}
+		}
+
+		return &tls.Config{GetCertificate: manager.GetCertificate}, manager.HTTPHandler(nil), nil
+
+	case secure.SelfSigned && len(secure.CertificateFile) == 0 && len(secure.KeyFile) == 0:
+		cert, err := generateSelfSignedCertificate(secure.SelfSignedHosts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &tls.Config{Certificates: []tls.Certificate{cert}}, nil, nil
+
+	default:
+		return nil, nil, nil
+	}
+
+}
+
+// generateSelfSignedCertificate creates an in-memory, self-signed ECDSA certificate covering "localhost" plus any
+// extra hostnames given, valid for a year -- enough to run HTTPS locally during development without provisioning
+// real certificate files.
+func generateSelfSignedCertificate(extraHosts []string) (tls.Certificate, error) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("unable to generate self-signed key: %s", err.Error())
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("unable to generate self-signed serial number: %s", err.Error())
+	}
+
+	hosts := append([]string{"localhost"}, extraHosts...)
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "softserve self-signed"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              hosts,
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("unable to create self-signed certificate: %s", err.Error())
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{derBytes},
+		PrivateKey:  key,
+	}, nil
+
+}